@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/wfen/dingy/experiments/statemachine"
+)
+
+// StateType identifies a state in the FSM.
+type StateType = statemachine.StateType
+
+// EventType identifies an event that can trigger a transition.
+type EventType = statemachine.EventType
+
+// Transition describes the target state reached when an event fires,
+// along with the optional guard that can veto it and the payload type it
+// expects.
+type Transition = statemachine.MachineTransition
+
+// NoOp is returned by an Action that does not want to chain into another
+// event.
+const NoOp EventType = ""
+
+// Default is consulted when the machine has not yet entered any state.
+const Default StateType = ""
+
+// ErrEventRejected is returned when an event has no transition defined for
+// the machine's current state.
+var ErrEventRejected = errors.New("lightswitch: event rejected")
+
+// EventContext carries the request context and payload that produced an
+// Action.Execute call, so guards and actions further down the chain can
+// see cancellation and recover a typed payload via statemachine.PayloadAs.
+type EventContext struct {
+	Context context.Context
+	Payload any
+}
+
+// Action runs when its owning state is entered.
+type Action interface {
+	Execute(eventCtx EventContext) EventType
+}
+
+// Events maps an EventType to the Transition it triggers.
+type Events map[EventType]Transition
+
+// State couples the Action run on entry with the events it accepts.
+type State struct {
+	Action Action
+	Events Events
+}
+
+// States maps a StateType to its definition.
+type States map[StateType]State
+
+// StateMachine is a small FSM driven by State/Events tables, in the style
+// of newLightSwitchFSM.
+type StateMachine struct {
+	Previous StateType
+	Current  StateType
+	States   States
+
+	busOnce sync.Once
+	bus     *statemachine.EventBus
+}
+
+// Subscribe returns a channel of statemachine.TransitionEvents published
+// after each successful SendEventWithContext, matching query, and a
+// CancelFunc that unsubscribes and closes the channel.
+func (s *StateMachine) Subscribe(query statemachine.TransitionQuery) (<-chan statemachine.TransitionEvent, statemachine.CancelFunc) {
+	s.busOnce.Do(s.initBus)
+	return s.bus.Subscribe(query, 64, statemachine.DropOldest)
+}
+
+// SubscribeUnbuffered returns a channel of statemachine.TransitionEvents
+// that SendEventWithContext sends to synchronously, so a hook reading this
+// channel has received each event before SendEventWithContext returns;
+// whatever the hook does after the receive is not waited for. Useful
+// for audit logging or implementing OnExit/OnEntry alongside an Action.
+func (s *StateMachine) SubscribeUnbuffered(query statemachine.TransitionQuery) (<-chan statemachine.TransitionEvent, statemachine.CancelFunc) {
+	s.busOnce.Do(s.initBus)
+	return s.bus.SubscribeUnbuffered(query)
+}
+
+func (s *StateMachine) initBus() {
+	s.bus = statemachine.NewEventBus()
+}
+
+func (s *StateMachine) getTransition(event EventType) (Transition, error) {
+	if state, ok := s.States[s.Current]; ok {
+		if state.Events != nil {
+			if t, ok := state.Events[event]; ok {
+				return t, nil
+			}
+		}
+	}
+	return Transition{}, ErrEventRejected
+}
+
+// SendEventWithContext drives the machine with event, carrying payload,
+// chaining through any follow-up events an Action's Execute returns until
+// one returns NoOp. It returns ErrEventRejected if the current state has no
+// transition for event or payload doesn't match the transition's
+// PayloadType, or an *statemachine.ErrGuardRejected wrapping the error a
+// transition's Guard vetoes it with.
+func (s *StateMachine) SendEventWithContext(ctx context.Context, event EventType, payload any) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		t, err := s.getTransition(event)
+		if err != nil {
+			return err
+		}
+		if t.PayloadType != nil && (payload == nil || reflect.TypeOf(payload) != t.PayloadType) {
+			return fmt.Errorf("%w: payload type %T does not match expected %s", ErrEventRejected, payload, t.PayloadType)
+		}
+		if t.Guard != nil {
+			if err := t.Guard(ctx, payload); err != nil {
+				return &statemachine.ErrGuardRejected{Reason: err}
+			}
+		}
+
+		from := s.Current
+		s.Previous = from
+		s.Current = t.To
+
+		if s.bus != nil {
+			payloadBytes, _ := payload.([]byte)
+			s.bus.Publish(statemachine.TransitionEvent{
+				From:      string(from),
+				To:        string(t.To),
+				Event:     string(event),
+				Timestamp: time.Now(),
+				Payload:   payloadBytes,
+			})
+		}
+
+		state, ok := s.States[s.Current]
+		if !ok || state.Action == nil {
+			return nil
+		}
+
+		nextEvent := state.Action.Execute(EventContext{Context: ctx, Payload: payload})
+		if nextEvent == NoOp {
+			return nil
+		}
+		event = nextEvent
+		payload = nil
+	}
+}
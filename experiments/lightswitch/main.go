@@ -31,19 +31,19 @@ func newLightSwitchFSM() *StateMachine {
 		States: States{
 			Default: State{
 				Events: Events{
-					SwitchOff: Off,
+					SwitchOff: Transition{To: Off},
 				},
 			},
 			Off: State{
 				Action: &OffAction{},
 				Events: Events{
-					SwitchOn: On,
+					SwitchOn: Transition{To: On},
 				},
 			},
 			On: State{
 				Action: &OnAction{},
 				Events: Events{
-					SwitchOff: Off,
+					SwitchOff: Transition{To: Off},
 				},
 			},
 		},
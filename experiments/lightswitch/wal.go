@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/wfen/dingy/experiments/statemachine"
+)
+
+// withWAL adds crash recovery to StateMachine. ID names the machine in the
+// shared WAL; WAL is nil until Restore attaches one (or it is set directly). ReplayMode
+// suppresses Action.Execute while entries are being replayed, since actions
+// are assumed to be side-effectful and must not re-fire on restart.
+type withWAL struct {
+	ID         string
+	WAL        statemachine.WAL
+	ReplayMode bool
+}
+
+// StateMachineWAL pairs a StateMachine with WAL-backed crash recovery.
+type StateMachineWAL struct {
+	*StateMachine
+	withWAL
+}
+
+// NewStateMachineWAL wraps sm with a WAL identified by id.
+func NewStateMachineWAL(id string, sm *StateMachine) *StateMachineWAL {
+	return &StateMachineWAL{StateMachine: sm, withWAL: withWAL{ID: id}}
+}
+
+// SendEventWithContext mirrors StateMachine.SendEventWithContext but
+// appends every transition to the attached WAL (synchronously, before the
+// in-memory state changes) and withholds Action.Execute while ReplayMode is
+// set.
+func (s *StateMachineWAL) SendEventWithContext(ctx context.Context, event EventType, payload any) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		t, err := s.getTransition(event)
+		if err != nil {
+			return err
+		}
+		if t.PayloadType != nil && (payload == nil || reflect.TypeOf(payload) != t.PayloadType) {
+			return fmt.Errorf("%w: payload type %T does not match expected %s", ErrEventRejected, payload, t.PayloadType)
+		}
+		if t.Guard != nil {
+			if err := t.Guard(ctx, payload); err != nil {
+				return &statemachine.ErrGuardRejected{Reason: err}
+			}
+		}
+
+		payloadBytes, _ := payload.([]byte)
+		if s.WAL != nil && !s.ReplayMode {
+			if err := s.WAL.AppendTransition(s.ID, string(s.Current), string(event), string(t.To), payloadBytes); err != nil {
+				return err
+			}
+		}
+
+		from := s.Current
+		s.Previous = from
+		s.Current = t.To
+
+		if s.bus != nil && !s.ReplayMode {
+			s.bus.Publish(statemachine.TransitionEvent{
+				MachineID: s.ID,
+				From:      string(from),
+				To:        string(t.To),
+				Event:     string(event),
+				Timestamp: time.Now(),
+				Payload:   payloadBytes,
+			})
+		}
+
+		state, ok := s.States[s.Current]
+		if !ok || state.Action == nil || s.ReplayMode {
+			return nil
+		}
+
+		nextEvent := state.Action.Execute(EventContext{Context: ctx, Payload: payload})
+		if nextEvent == NoOp {
+			return nil
+		}
+		event = nextEvent
+		payload = nil
+	}
+}
+
+// Restore reopens the WAL rooted at walPath and replays every recorded
+// transition for s.ID to rebuild Current, without re-running Actions.
+func (s *StateMachineWAL) Restore(walPath string) error {
+	wal, err := statemachine.NewFileWAL(walPath, 0)
+	if err != nil {
+		return err
+	}
+	entries, err := wal.Replay(s.ID)
+	if err != nil {
+		return err
+	}
+
+	s.ReplayMode = true
+	for _, e := range entries {
+		s.Previous = s.Current
+		s.Current = StateType(e.To)
+	}
+	s.ReplayMode = false
+
+	s.WAL = wal
+	return nil
+}
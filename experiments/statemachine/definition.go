@@ -0,0 +1,354 @@
+package statemachine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a declarative definition's serialization.
+type Format string
+
+const (
+	FormatTOML Format = "toml"
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// Definition is the declarative shape of an FSM: an id, the state to start
+// in, and a table of states. Each state lists its outgoing transitions
+// (event -> target state name) and may name an entry/exit action and, per
+// event, a guard to resolve through a Registry.
+type Definition struct {
+	ID      string              `toml:"id" yaml:"id" json:"id"`
+	Initial string              `toml:"initial" yaml:"initial" json:"initial"`
+	States  map[string]StateDef `toml:"states" yaml:"states" json:"states"`
+}
+
+// StateDef is one state's entry in a Definition.
+type StateDef struct {
+	On     map[string]string `toml:"on" yaml:"on" json:"on"`
+	Entry  string            `toml:"entry" yaml:"entry" json:"entry"`
+	Exit   string            `toml:"exit" yaml:"exit" json:"exit"`
+	Guards map[string]string `toml:"guards" yaml:"guards" json:"guards"`
+}
+
+// ValidationError reports a problem found while loading a Definition. Line
+// is only populated for TOML parse errors and YAML duplicate keys.
+type ValidationError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	if e.File == "" {
+		return e.Msg
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Msg)
+}
+
+// LoadMachine reads a declarative FSM definition from path, detecting its
+// format (.toml, .yaml/.yml, or .json) from the extension, and builds a
+// Machine from it.
+func LoadMachine(path string, registry *Registry) (*Machine, error) {
+	format, err := formatFromExt(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("statemachine: reading %s: %w", path, err)
+	}
+	return load(data, format, path, registry)
+}
+
+// LoadFromReader reads a declarative FSM definition of the given format
+// from r and builds a Machine from it.
+func LoadFromReader(r io.Reader, format Format, registry *Registry) (*Machine, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("statemachine: reading definition: %w", err)
+	}
+	return load(data, format, "", registry)
+}
+
+// LoadFromBytes parses a declarative FSM definition of the given format and
+// builds a Machine from it.
+func LoadFromBytes(data []byte, format Format, registry *Registry) (*Machine, error) {
+	return load(data, format, "", registry)
+}
+
+func formatFromExt(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("statemachine: cannot infer format from %s", path)
+	}
+}
+
+func load(data []byte, format Format, file string, registry *Registry) (*Machine, error) {
+	if err := checkDuplicateKeys(data, format, file); err != nil {
+		return nil, err
+	}
+
+	var def Definition
+	switch format {
+	case FormatTOML:
+		if _, err := toml.Decode(string(data), &def); err != nil {
+			if perr, ok := err.(toml.ParseError); ok {
+				return nil, &ValidationError{File: file, Line: perr.Position.Line, Msg: perr.Error()}
+			}
+			return nil, &ValidationError{File: file, Msg: err.Error()}
+		}
+	case FormatYAML:
+		if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&def); err != nil {
+			return nil, &ValidationError{File: file, Msg: err.Error()}
+		}
+	case FormatJSON:
+		if err := json.NewDecoder(bytes.NewReader(data)).Decode(&def); err != nil {
+			return nil, &ValidationError{File: file, Msg: err.Error()}
+		}
+	default:
+		return nil, &ValidationError{File: file, Msg: fmt.Sprintf("unsupported format %q", format)}
+	}
+
+	if err := validate(&def, file); err != nil {
+		return nil, err
+	}
+
+	m := &Machine{
+		ID:      def.ID,
+		Initial: StateType(def.Initial),
+		States:  make(StateMap, len(def.States)),
+		hooks:   make(map[StateType]stateHooks, len(def.States)),
+	}
+	for name, sd := range def.States {
+		transitions := make(TransitionMap, len(sd.On))
+		for event, target := range sd.On {
+			mt := MachineTransition{To: StateType(target)}
+			if guardName, ok := sd.Guards[event]; ok && registry != nil {
+				fn, err := registry.guard(guardName)
+				if err != nil {
+					return nil, &ValidationError{File: file, Msg: fmt.Sprintf("state %q: %s", name, err)}
+				}
+				mt.Guard = fn
+			}
+			transitions[EventType(event)] = mt
+		}
+		m.States[StateType(name)] = MachineState{On: transitions}
+
+		var h stateHooks
+		if registry != nil && sd.Entry != "" {
+			fn, err := registry.action(sd.Entry)
+			if err != nil {
+				return nil, &ValidationError{File: file, Msg: fmt.Sprintf("state %q: %s", name, err)}
+			}
+			h.Entry = fn
+		}
+		if registry != nil && sd.Exit != "" {
+			fn, err := registry.action(sd.Exit)
+			if err != nil {
+				return nil, &ValidationError{File: file, Msg: fmt.Sprintf("state %q: %s", name, err)}
+			}
+			h.Exit = fn
+		}
+		if h.Entry != nil || h.Exit != nil {
+			m.hooks[StateType(name)] = h
+		}
+	}
+	return m, nil
+}
+
+// validate checks a Definition for the mistakes LoadMachine cannot recover
+// from: a missing initial state, transitions that target a state that
+// doesn't exist, and states that can never be reached. A fourth check, a
+// state defining the same event twice, runs separately via
+// checkDuplicateKeys before def is even decoded, since StateDef.On can no
+// longer tell duplicate definitions apart by the time validate sees it.
+func validate(def *Definition, file string) error {
+	if def.Initial == "" {
+		return &ValidationError{File: file, Msg: "missing initial state"}
+	}
+	if _, ok := def.States[def.Initial]; !ok {
+		return &ValidationError{File: file, Msg: fmt.Sprintf("initial state %q is not defined", def.Initial)}
+	}
+
+	for name, sd := range def.States {
+		for event, target := range sd.On {
+			if _, ok := def.States[target]; !ok {
+				return &ValidationError{File: file, Msg: fmt.Sprintf("state %q: event %q targets undefined state %q", name, event, target)}
+			}
+		}
+	}
+
+	reachable := map[string]bool{def.Initial: true}
+	queue := []string{def.Initial}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, target := range def.States[name].On {
+			if !reachable[target] {
+				reachable[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	for name := range def.States {
+		if !reachable[name] {
+			return &ValidationError{File: file, Msg: fmt.Sprintf("state %q is unreachable from initial state %q", name, def.Initial)}
+		}
+	}
+	return nil
+}
+
+// checkDuplicateKeys catches a state defining the same event (or any
+// other key) twice, which StateDef.On's map[string]string would
+// otherwise silently collapse to whichever definition decoded last
+// before validate ever saw it. TOML already rejects a duplicate key
+// within the same table at parse time, so only JSON and YAML -- whose
+// decoders both apply last-value-wins instead of erroring -- need a
+// dedicated pass over the raw, pre-decode document.
+func checkDuplicateKeys(data []byte, format Format, file string) error {
+	switch format {
+	case FormatJSON:
+		return checkDuplicateKeysJSON(data, file)
+	case FormatYAML:
+		return checkDuplicateKeysYAML(data, file)
+	default:
+		return nil
+	}
+}
+
+func checkDuplicateKeysJSON(data []byte, file string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		// Malformed JSON is reported by the real decode pass below.
+		return nil
+	}
+	if err := decodeJSONNoDup(dec, tok, nil); err != nil {
+		return &ValidationError{File: file, Msg: err.Error()}
+	}
+	return nil
+}
+
+// decodeJSONNoDup walks dec starting from tok, erroring the first time an
+// object defines the same key twice.
+func decodeJSONNoDup(dec *json.Decoder, tok json.Token, path []string) error {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		seen := make(map[string]bool)
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			key, _ := keyTok.(string)
+			if seen[key] {
+				return fmt.Errorf("duplicate key %q at %s", key, strings.Join(append(path, key), "."))
+			}
+			seen[key] = true
+
+			valTok, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			if err := decodeJSONNoDup(dec, valTok, append(path, key)); err != nil {
+				return err
+			}
+		}
+		_, _ = dec.Token() // consume the closing '}'
+	case '[':
+		for i := 0; dec.More(); i++ {
+			valTok, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			if err := decodeJSONNoDup(dec, valTok, append(path, fmt.Sprintf("[%d]", i))); err != nil {
+				return err
+			}
+		}
+		_, _ = dec.Token() // consume the closing ']'
+	}
+	return nil
+}
+
+func checkDuplicateKeysYAML(data []byte, file string) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		// Malformed YAML is reported by the real decode pass below.
+		return nil
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	if err := walkYAMLNoDup(root.Content[0], nil); err != nil {
+		return &ValidationError{File: file, Line: err.(*yamlDupError).line, Msg: err.Error()}
+	}
+	return nil
+}
+
+// yamlDupError carries the line of a duplicate mapping key, so
+// checkDuplicateKeysYAML can populate ValidationError.Line the way a TOML
+// parse error already does.
+type yamlDupError struct {
+	line int
+	msg  string
+}
+
+func (e *yamlDupError) Error() string { return e.msg }
+
+// walkYAMLNoDup walks node, erroring the first time a mapping defines the
+// same key twice. yaml.Node preserves every key-value pair in document
+// order, including duplicates that yaml.Unmarshal into a Go map would
+// otherwise silently collapse.
+func walkYAMLNoDup(node *yaml.Node, path []string) error {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		seen := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valNode := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			if seen[key] {
+				return &yamlDupError{
+					line: keyNode.Line,
+					msg:  fmt.Sprintf("duplicate key %q at %s", key, strings.Join(append(path, key), ".")),
+				}
+			}
+			seen[key] = true
+			if err := walkYAMLNoDup(valNode, append(path, key)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := walkYAMLNoDup(child, append(path, fmt.Sprintf("[%d]", i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
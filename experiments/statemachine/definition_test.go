@@ -0,0 +1,114 @@
+package statemachine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const turnstileJSON = `{
+	"id": "turnstile",
+	"initial": "locked",
+	"states": {
+		"locked": {"on": {"COIN": "unlocked"}},
+		"unlocked": {"on": {"PUSH": "locked"}}
+	}
+}`
+
+const turnstileYAML = `
+id: turnstile
+initial: locked
+states:
+  locked:
+    on:
+      COIN: unlocked
+  unlocked:
+    on:
+      PUSH: locked
+`
+
+const turnstileTOML = `
+id = "turnstile"
+initial = "locked"
+
+[states.locked.on]
+COIN = "unlocked"
+
+[states.unlocked.on]
+PUSH = "locked"
+`
+
+func TestLoadFromBytesAllFormats(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		data   string
+	}{
+		{FormatJSON, turnstileJSON},
+		{FormatYAML, turnstileYAML},
+		{FormatTOML, turnstileTOML},
+	} {
+		m, err := LoadFromBytes([]byte(tc.data), tc.format, nil)
+		if err != nil {
+			t.Fatalf("LoadFromBytes(%s): %v", tc.format, err)
+		}
+		if m.Initial != "locked" {
+			t.Fatalf("LoadFromBytes(%s): Initial = %q, want %q", tc.format, m.Initial, "locked")
+		}
+		if _, ok := m.States["unlocked"].On["PUSH"]; !ok {
+			t.Fatalf("LoadFromBytes(%s): missing unlocked.PUSH transition", tc.format)
+		}
+	}
+}
+
+func TestLoadFromBytesMissingInitial(t *testing.T) {
+	_, err := LoadFromBytes([]byte(`{"id":"x","states":{"a":{}}}`), FormatJSON, nil)
+	if err == nil || !strings.Contains(err.Error(), "missing initial state") {
+		t.Fatalf("err = %v, want a missing-initial-state error", err)
+	}
+}
+
+func TestLoadFromBytesUnknownTransitionTarget(t *testing.T) {
+	data := `{"id":"x","initial":"a","states":{"a":{"on":{"go":"nowhere"}}}}`
+	_, err := LoadFromBytes([]byte(data), FormatJSON, nil)
+	if err == nil || !strings.Contains(err.Error(), `targets undefined state "nowhere"`) {
+		t.Fatalf("err = %v, want an undefined-target error", err)
+	}
+}
+
+func TestLoadFromBytesUnreachableState(t *testing.T) {
+	data := `{"id":"x","initial":"a","states":{"a":{},"b":{}}}`
+	_, err := LoadFromBytes([]byte(data), FormatJSON, nil)
+	if err == nil || !strings.Contains(err.Error(), `state "b" is unreachable`) {
+		t.Fatalf("err = %v, want an unreachable-state error", err)
+	}
+}
+
+func TestLoadFromBytesDuplicateEventJSON(t *testing.T) {
+	data := `{"id":"x","initial":"a","states":{"a":{"on":{"go":"b","go":"c"}},"b":{},"c":{}}}`
+	_, err := LoadFromBytes([]byte(data), FormatJSON, nil)
+	if err == nil || !strings.Contains(err.Error(), `duplicate key "go"`) {
+		t.Fatalf("err = %v, want a duplicate-key error", err)
+	}
+}
+
+func TestLoadFromBytesDuplicateEventYAML(t *testing.T) {
+	data := "id: x\ninitial: a\nstates:\n  a:\n    on:\n      go: b\n      go: c\n  b: {}\n  c: {}\n"
+	_, err := LoadFromBytes([]byte(data), FormatYAML, nil)
+	if err == nil || !strings.Contains(err.Error(), `duplicate key "go"`) {
+		t.Fatalf("err = %v, want a duplicate-key error", err)
+	}
+}
+
+func TestLoadFromBytesResolvesGuardsAndActions(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterGuard("alwaysAllow", func(ctx context.Context, payload any) error { return nil })
+
+	data := `{"id":"x","initial":"a","states":{"a":{"on":{"go":"b"},"guards":{"go":"alwaysAllow"}},"b":{}}}`
+	m, err := LoadFromBytes([]byte(data), FormatJSON, registry)
+	if err != nil {
+		t.Fatalf("LoadFromBytes: %v", err)
+	}
+	if m.States["a"].On["go"].Guard == nil {
+		t.Fatal("expected guard \"alwaysAllow\" to be resolved onto the a.go transition")
+	}
+}
@@ -0,0 +1,171 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrNotLeader is returned by Transition when this process is not the
+// leader and no Transport is configured to proxy the request.
+var ErrNotLeader = errors.New("statemachine: not leader")
+
+// Handle is returned by Lock.Acquire. Token is a fencing token that
+// increases with every successful acquisition, so a StateStore can reject
+// a write from a leader that held the lock before a partition and hasn't
+// yet noticed it lost it.
+type Handle struct {
+	Token uint64
+}
+
+// Lock is a pluggable mutual-exclusion primitive scoped to a named
+// resource, such as a distributed lock backed by etcd or Consul.
+type Lock interface {
+	Acquire(id string, ttl time.Duration) (Handle, error)
+	Release(id string, h Handle) error
+}
+
+// Leader is a pluggable leader-election primitive. Elect blocks until id
+// becomes leader, then returns a channel that is closed when leadership is
+// lost.
+type Leader interface {
+	Elect(id string) (<-chan struct{}, error)
+}
+
+// StateStore is the pluggable storage for a DistributedMachine's shared
+// current state. Put should reject a token that is not newer than the one
+// it last accepted, so a stale leader can't corrupt state after rejoining.
+type StateStore interface {
+	Get(machineID string) (state string, token uint64, err error)
+	Put(machineID, state string, token uint64) error
+}
+
+// Transport proxies a Transition request to whichever process currently
+// holds leadership.
+type Transport interface {
+	Transition(machineID, event string) (string, error)
+}
+
+// DistributedMachine coordinates a shared FSM across a cluster so that
+// only the elected leader applies events to it.
+type DistributedMachine struct {
+	ID      string
+	Initial StateType
+	States  StateMap
+
+	Lock      Lock
+	Leader    Leader
+	Store     StateStore
+	Transport Transport
+	LockTTL   time.Duration
+
+	mu       sync.Mutex
+	isLeader bool
+}
+
+// Run starts leader election for m.ID and tracks leadership as it is
+// gained and lost. It blocks until ctx is canceled or Elect returns an
+// error.
+func (m *DistributedMachine) Run(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		lost, err := m.Leader.Elect(m.ID)
+		if err != nil {
+			return fmt.Errorf("statemachine: electing leader: %w", err)
+		}
+
+		m.mu.Lock()
+		m.isLeader = true
+		m.mu.Unlock()
+
+		select {
+		case <-lost:
+		case <-ctx.Done():
+		}
+
+		m.mu.Lock()
+		m.isLeader = false
+		m.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (m *DistributedMachine) leading() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.isLeader
+}
+
+// Transition applies event, carrying payload, to the shared FSM and
+// returns the resulting state. If this process isn't leader, it proxies
+// through Transport (if configured) or returns ErrNotLeader. It returns
+// ErrContextCanceled if ctx is already done, ErrNoSuchTransition if the
+// current state has no transition for event or payload doesn't match the
+// transition's PayloadType, or an *ErrGuardRejected if the transition's
+// Guard vetoes it -- the same checks Machine.TransitionWithContext makes,
+// so a Guard requiring, say, a valid auth token in ctx is enforced here
+// too rather than only for in-process machines.
+func (m *DistributedMachine) Transition(ctx context.Context, event string, payload any) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", ErrContextCanceled
+	}
+
+	if !m.leading() {
+		if m.Transport == nil {
+			return "", ErrNotLeader
+		}
+		return m.Transport.Transition(m.ID, event)
+	}
+
+	ttl := m.LockTTL
+	if ttl == 0 {
+		ttl = 5 * time.Second
+	}
+
+	handle, err := m.Lock.Acquire(m.ID, ttl)
+	if err != nil {
+		return "", fmt.Errorf("statemachine: acquiring lock: %w", err)
+	}
+	defer m.Lock.Release(m.ID, handle)
+
+	curStr, _, err := m.Store.Get(m.ID)
+	if err != nil {
+		return "", fmt.Errorf("statemachine: reading shared state: %w", err)
+	}
+	cur := StateType(curStr)
+	if cur == "" {
+		cur = m.Initial
+	}
+
+	state, ok := m.States[cur]
+	if !ok {
+		return string(cur), ErrNoSuchTransition
+	}
+	t, ok := state.On[EventType(event)]
+	if !ok {
+		return string(cur), ErrNoSuchTransition
+	}
+
+	if t.PayloadType != nil && (payload == nil || reflect.TypeOf(payload) != t.PayloadType) {
+		return string(cur), fmt.Errorf("%w: payload type %T does not match expected %s", ErrNoSuchTransition, payload, t.PayloadType)
+	}
+	if t.Guard != nil {
+		if err := t.Guard(ctx, payload); err != nil {
+			return string(cur), &ErrGuardRejected{Reason: err}
+		}
+	}
+
+	if err := m.Store.Put(m.ID, string(t.To), handle.Token); err != nil {
+		return "", fmt.Errorf("statemachine: writing shared state: %w", err)
+	}
+	return string(t.To), nil
+}
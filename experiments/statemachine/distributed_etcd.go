@@ -0,0 +1,160 @@
+//go:build etcd
+
+package statemachine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// EtcdLock is a Lock backed by an etcd lease-based mutex.
+type EtcdLock struct {
+	client *clientv3.Client
+
+	sessions map[string]*concurrency.Session
+	muxes    map[string]*concurrency.Mutex
+}
+
+// NewEtcdLock returns a Lock that acquires its mutexes against client.
+func NewEtcdLock(client *clientv3.Client) *EtcdLock {
+	return &EtcdLock{
+		client:   client,
+		sessions: make(map[string]*concurrency.Session),
+		muxes:    make(map[string]*concurrency.Mutex),
+	}
+}
+
+// Acquire implements Lock.
+func (l *EtcdLock) Acquire(id string, ttl time.Duration) (Handle, error) {
+	session, err := concurrency.NewSession(l.client, concurrency.WithTTL(int(ttl.Seconds())))
+	if err != nil {
+		return Handle{}, fmt.Errorf("statemachine: opening etcd session: %w", err)
+	}
+	mux := concurrency.NewMutex(session, "/dinghy/locks/"+id)
+	if err := mux.Lock(context.Background()); err != nil {
+		session.Close()
+		return Handle{}, fmt.Errorf("statemachine: acquiring etcd lock: %w", err)
+	}
+	l.sessions[id] = session
+	l.muxes[id] = mux
+	return Handle{Token: uint64(session.Lease())}, nil
+}
+
+// Release implements Lock.
+func (l *EtcdLock) Release(id string, h Handle) error {
+	mux, ok := l.muxes[id]
+	if !ok {
+		return fmt.Errorf("statemachine: no held etcd lock for %q", id)
+	}
+	defer delete(l.muxes, id)
+	defer delete(l.sessions, id)
+	if err := mux.Unlock(context.Background()); err != nil {
+		return fmt.Errorf("statemachine: releasing etcd lock: %w", err)
+	}
+	return l.sessions[id].Close()
+}
+
+// EtcdLeader is a Leader backed by etcd's concurrency.Election.
+type EtcdLeader struct {
+	client *clientv3.Client
+}
+
+// NewEtcdLeader returns a Leader that elects against client.
+func NewEtcdLeader(client *clientv3.Client) *EtcdLeader {
+	return &EtcdLeader{client: client}
+}
+
+// Elect implements Leader.
+func (l *EtcdLeader) Elect(id string) (<-chan struct{}, error) {
+	session, err := concurrency.NewSession(l.client)
+	if err != nil {
+		return nil, fmt.Errorf("statemachine: opening etcd session: %w", err)
+	}
+	election := concurrency.NewElection(session, "/dinghy/election")
+	if err := election.Campaign(context.Background(), id); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("statemachine: campaigning for leadership: %w", err)
+	}
+
+	lost := make(chan struct{})
+	go func() {
+		<-session.Done()
+		close(lost)
+	}()
+	return lost, nil
+}
+
+// EtcdStateStore is a StateStore backed by an etcd key per machine. The
+// fencing token is stored alongside the state and checked with a
+// transaction so a stale writer can't overwrite a newer one.
+type EtcdStateStore struct {
+	client *clientv3.Client
+}
+
+// NewEtcdStateStore returns a StateStore keyed on client.
+func NewEtcdStateStore(client *clientv3.Client) *EtcdStateStore {
+	return &EtcdStateStore{client: client}
+}
+
+func (s *EtcdStateStore) key(machineID string) string {
+	return "/dinghy/state/" + machineID
+}
+
+// Get implements StateStore.
+func (s *EtcdStateStore) Get(machineID string) (string, uint64, error) {
+	resp, err := s.client.Get(context.Background(), s.key(machineID))
+	if err != nil {
+		return "", 0, fmt.Errorf("statemachine: reading etcd state: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return "", 0, nil
+	}
+	var state string
+	var token uint64
+	if _, err := fmt.Sscanf(string(resp.Kvs[0].Value), "%d:%s", &token, &state); err != nil {
+		return "", 0, fmt.Errorf("statemachine: decoding etcd state: %w", err)
+	}
+	return state, token, nil
+}
+
+// Put implements StateStore. It compares against the stored value in the
+// same transaction that writes the new one, so a leader that last read
+// the key before losing a race (or a partition) can't blindly overwrite
+// whatever a newer writer already committed.
+func (s *EtcdStateStore) Put(machineID, state string, token uint64) error {
+	key := s.key(machineID)
+
+	resp, err := s.client.Get(context.Background(), key)
+	if err != nil {
+		return fmt.Errorf("statemachine: reading etcd state: %w", err)
+	}
+
+	var cmp clientv3.Cmp
+	var curToken uint64
+	if len(resp.Kvs) == 0 {
+		cmp = clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	} else {
+		cur := resp.Kvs[0].Value
+		if _, err := fmt.Sscanf(string(cur), "%d:", &curToken); err != nil {
+			return fmt.Errorf("statemachine: decoding etcd state: %w", err)
+		}
+		cmp = clientv3.Compare(clientv3.Value(key), "=", string(cur))
+	}
+	if token <= curToken {
+		return fmt.Errorf("statemachine: stale fencing token %d for %q (last accepted %d)", token, machineID, curToken)
+	}
+
+	value := fmt.Sprintf("%d:%s", token, state)
+	txnResp, err := s.client.Txn(context.Background()).If(cmp).Then(clientv3.OpPut(key, value)).Commit()
+	if err != nil {
+		return fmt.Errorf("statemachine: writing etcd state: %w", err)
+	}
+	if !txnResp.Succeeded {
+		return fmt.Errorf("statemachine: stale fencing token %d for %q: lost a race with a concurrent writer", token, machineID)
+	}
+	return nil
+}
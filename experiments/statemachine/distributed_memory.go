@@ -0,0 +1,123 @@
+package statemachine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryLock is an in-process Lock, useful for tests and single-binary
+// deployments. It ignores ttl: a held lock stays held until Release.
+type MemoryLock struct {
+	mu      sync.Mutex
+	held    map[string]Handle
+	nextTok uint64
+}
+
+// NewMemoryLock returns an empty MemoryLock.
+func NewMemoryLock() *MemoryLock {
+	return &MemoryLock{held: make(map[string]Handle)}
+}
+
+// Acquire implements Lock.
+func (l *MemoryLock) Acquire(id string, ttl time.Duration) (Handle, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.held[id]; ok {
+		return Handle{}, fmt.Errorf("statemachine: lock %q already held", id)
+	}
+	l.nextTok++
+	h := Handle{Token: l.nextTok}
+	l.held[id] = h
+	return h, nil
+}
+
+// Release implements Lock.
+func (l *MemoryLock) Release(id string, h Handle) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cur, ok := l.held[id]
+	if !ok || cur.Token != h.Token {
+		return fmt.Errorf("statemachine: handle does not hold lock %q", id)
+	}
+	delete(l.held, id)
+	return nil
+}
+
+// MemoryLeader is a single-process Leader: whichever id calls Elect first
+// becomes leader and stays leader until Resign is called.
+type MemoryLeader struct {
+	mu      sync.Mutex
+	leader  string
+	lostChs map[string]chan struct{}
+}
+
+// NewMemoryLeader returns a MemoryLeader with no leader elected.
+func NewMemoryLeader() *MemoryLeader {
+	return &MemoryLeader{lostChs: make(map[string]chan struct{})}
+}
+
+// Elect implements Leader, except that it does not block: if another id
+// is already leader, it returns an error at once.
+func (l *MemoryLeader) Elect(id string) (<-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.leader != "" && l.leader != id {
+		return nil, fmt.Errorf("statemachine: %q is already leader", l.leader)
+	}
+	l.leader = id
+	ch := make(chan struct{})
+	l.lostChs[id] = ch
+	return ch, nil
+}
+
+// Resign gives up leadership held under id, closing its lost channel.
+func (l *MemoryLeader) Resign(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.leader != id {
+		return
+	}
+	l.leader = ""
+	if ch, ok := l.lostChs[id]; ok {
+		close(ch)
+		delete(l.lostChs, id)
+	}
+}
+
+// MemoryStateStore is an in-process StateStore that enforces the same
+// fencing-token ordering a networked store would.
+type MemoryStateStore struct {
+	mu    sync.Mutex
+	state map[string]string
+	token map[string]uint64
+}
+
+// NewMemoryStateStore returns an empty MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{state: make(map[string]string), token: make(map[string]uint64)}
+}
+
+// Get implements StateStore.
+func (s *MemoryStateStore) Get(machineID string) (string, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state[machineID], s.token[machineID], nil
+}
+
+// Put implements StateStore.
+func (s *MemoryStateStore) Put(machineID, state string, token uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token <= s.token[machineID] {
+		return fmt.Errorf("statemachine: stale fencing token %d for %q (last accepted %d)", token, machineID, s.token[machineID])
+	}
+	s.state[machineID] = state
+	s.token[machineID] = token
+	return nil
+}
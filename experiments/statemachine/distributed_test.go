@@ -0,0 +1,161 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestDistributedMachine() (*DistributedMachine, *MemoryLeader) {
+	leader := NewMemoryLeader()
+	m := &DistributedMachine{
+		ID:      "turnstile",
+		Initial: "locked",
+		States: StateMap{
+			"locked": MachineState{On: TransitionMap{
+				"COIN": MachineTransition{To: "unlocked"},
+			}},
+			"unlocked": MachineState{On: TransitionMap{
+				"PUSH": MachineTransition{To: "locked"},
+			}},
+		},
+		Lock:   NewMemoryLock(),
+		Leader: leader,
+		Store:  NewMemoryStateStore(),
+	}
+	return m, leader
+}
+
+func TestDistributedMachineTransitionRequiresLeadership(t *testing.T) {
+	m, _ := newTestDistributedMachine()
+
+	_, err := m.Transition(context.Background(), "COIN", nil)
+	if !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("Transition() before election = %v, want ErrNotLeader", err)
+	}
+}
+
+func TestDistributedMachineTransitionAppliesOnceLeader(t *testing.T) {
+	m, leader := newTestDistributedMachine()
+
+	if _, err := leader.Elect(m.ID); err != nil {
+		t.Fatalf("Elect: %v", err)
+	}
+	m.mu.Lock()
+	m.isLeader = true
+	m.mu.Unlock()
+
+	state, err := m.Transition(context.Background(), "COIN", nil)
+	if err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+	if state != "unlocked" {
+		t.Fatalf("Transition() = %q, want %q", state, "unlocked")
+	}
+
+	got, token, err := m.Store.Get(m.ID)
+	if err != nil {
+		t.Fatalf("Store.Get: %v", err)
+	}
+	if got != "unlocked" || token == 0 {
+		t.Fatalf("Store.Get() = (%q, %d), want (%q, non-zero)", got, token, "unlocked")
+	}
+}
+
+func TestDistributedMachineTransitionNoSuchTransition(t *testing.T) {
+	m, leader := newTestDistributedMachine()
+	leader.Elect(m.ID)
+	m.mu.Lock()
+	m.isLeader = true
+	m.mu.Unlock()
+
+	if _, err := m.Transition(context.Background(), "PUSH", nil); !errors.Is(err, ErrNoSuchTransition) {
+		t.Fatalf("Transition(PUSH) from locked = %v, want ErrNoSuchTransition", err)
+	}
+}
+
+func TestDistributedMachineTransitionContextCanceled(t *testing.T) {
+	m, leader := newTestDistributedMachine()
+	leader.Elect(m.ID)
+	m.mu.Lock()
+	m.isLeader = true
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.Transition(ctx, "COIN", nil); !errors.Is(err, ErrContextCanceled) {
+		t.Fatalf("Transition() with canceled ctx = %v, want ErrContextCanceled", err)
+	}
+}
+
+func TestDistributedMachineTransitionGuardRejects(t *testing.T) {
+	m, leader := newTestDistributedMachine()
+	m.States["locked"] = MachineState{On: TransitionMap{
+		"COIN": MachineTransition{
+			To: "unlocked",
+			Guard: func(ctx context.Context, payload any) error {
+				return errors.New("no coins accepted today")
+			},
+		},
+	}}
+	leader.Elect(m.ID)
+	m.mu.Lock()
+	m.isLeader = true
+	m.mu.Unlock()
+
+	_, err := m.Transition(context.Background(), "COIN", nil)
+	var rejected *ErrGuardRejected
+	if !errors.As(err, &rejected) {
+		t.Fatalf("Transition() = %v, want *ErrGuardRejected", err)
+	}
+}
+
+func TestMemoryLeaderResignClosesLostChannel(t *testing.T) {
+	leader := NewMemoryLeader()
+
+	lost, err := leader.Elect("a")
+	if err != nil {
+		t.Fatalf("Elect: %v", err)
+	}
+
+	select {
+	case <-lost:
+		t.Fatal("lost channel closed before Resign")
+	default:
+	}
+
+	leader.Resign("a")
+
+	select {
+	case <-lost:
+	default:
+		t.Fatal("lost channel not closed after Resign")
+	}
+
+	if _, err := leader.Elect("b"); err != nil {
+		t.Fatalf("Elect(b) after a's resignation: %v", err)
+	}
+}
+
+func TestMemoryStateStoreRejectsStaleFencingToken(t *testing.T) {
+	s := NewMemoryStateStore()
+
+	if err := s.Put("m1", "unlocked", 2); err != nil {
+		t.Fatalf("Put(token=2): %v", err)
+	}
+	if err := s.Put("m1", "locked", 1); err == nil {
+		t.Fatal("Put(token=1) after token=2 succeeded, want stale-token error")
+	}
+	if err := s.Put("m1", "locked", 2); err == nil {
+		t.Fatal("Put(token=2) after token=2 succeeded, want stale-token error")
+	}
+
+	state, token, err := s.Get("m1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if state != "unlocked" || token != 2 {
+		t.Fatalf("Get() = (%q, %d), want (%q, 2)", state, token, "unlocked")
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/wfen/dingy/experiments/statemachine"
 )
 
 func main() {
+	ctx := context.Background()
+
 	machine := statemachine.Machine{
 		ID:      "lock-unlock",
 		Initial: "locked",
@@ -33,20 +36,19 @@ func main() {
 			},
 		},
 	}
-	fmt.Printf("current: %v \t", machine.Current())
-	output := machine.Transition("COIN")
-	fmt.Printf("next: %v \n", output)
-
-	fmt.Printf("current: %v \t", machine.Current())
-	output = machine.Transition("COIN")
-	fmt.Printf("next: %v \n", output)
 
-	fmt.Printf("current: %v \t", machine.Current())
-	output = machine.Transition("PUSH")
-	fmt.Printf("next: %v \n", output)
-
-	fmt.Printf("current: %v \t", machine.Current())
-	output = machine.Transition("COIN")
-	fmt.Printf("next: %v \n", output)
+	send := func(event statemachine.EventType) {
+		fmt.Printf("current: %v \t", machine.Current())
+		output, err := machine.TransitionWithContext(ctx, event, nil)
+		if err != nil {
+			fmt.Printf("next: %v (rejected: %v) \n", output, err)
+			return
+		}
+		fmt.Printf("next: %v \n", output)
+	}
 
+	send("COIN")
+	send("COIN")
+	send("PUSH")
+	send("COIN")
 }
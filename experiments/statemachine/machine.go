@@ -0,0 +1,220 @@
+// Package statemachine implements a small finite state machine, as used by
+// the turnstile example in examples/lockUnlock.go.
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// defaultSubscriberBuffer sizes a Machine.Subscribe channel when the
+// machine hasn't been given an explicit SubscriberBufferSize.
+const defaultSubscriberBuffer = 64
+
+// StateType identifies a state in the FSM.
+type StateType string
+
+// EventType identifies an event that can trigger a transition.
+type EventType string
+
+// MachineTransition describes the target state reached when an event
+// fires, along with the optional guard that can veto it and the payload
+// type it expects.
+type MachineTransition struct {
+	To StateType
+
+	// Guard, if set, is evaluated before the transition is taken; a
+	// non-nil error vetoes it.
+	Guard GuardFunc
+
+	// PayloadType, if set, is checked against the runtime type of the
+	// payload passed to TransitionWithContext; a mismatch rejects the
+	// transition.
+	PayloadType reflect.Type
+}
+
+// TransitionMap maps an event to the transition it triggers.
+type TransitionMap map[EventType]MachineTransition
+
+// MachineState is the set of transitions available from a particular state.
+type MachineState struct {
+	On TransitionMap
+}
+
+// StateMap maps a state to its definition.
+type StateMap map[StateType]MachineState
+
+// ErrNoSuchTransition is returned when the machine's current state has no
+// transition for the given event.
+var ErrNoSuchTransition = errors.New("statemachine: no such transition")
+
+// ErrContextCanceled is returned when ctx is already done when
+// TransitionWithContext is called.
+var ErrContextCanceled = errors.New("statemachine: context canceled")
+
+// ErrGuardRejected is returned when a transition's Guard vetoes the event.
+// Reason is the error the Guard returned.
+type ErrGuardRejected struct {
+	Reason error
+}
+
+func (e *ErrGuardRejected) Error() string {
+	return fmt.Sprintf("statemachine: guard rejected transition: %v", e.Reason)
+}
+
+func (e *ErrGuardRejected) Unwrap() error { return e.Reason }
+
+// Machine is a finite state machine. If WAL is set, every transition is
+// durably appended before it is applied in memory so the machine can be
+// rebuilt with Restore after a crash.
+type Machine struct {
+	ID      string
+	Initial StateType
+	States  StateMap
+	WAL     WAL
+
+	// SubscriberBufferSize and SubscriberOverflow configure the channel
+	// Subscribe hands out; each Subscribe call reads them afresh.
+	SubscriberBufferSize int
+	SubscriberOverflow   OverflowPolicy
+
+	current   StateType
+	replaying bool
+	hooks     map[StateType]stateHooks
+
+	busOnce sync.Once
+	bus     *EventBus
+}
+
+// stateHooks are the resolved entry/exit actions for a state, set by
+// LoadMachine when a definition declares them.
+type stateHooks struct {
+	Entry ActionFunc
+	Exit  ActionFunc
+}
+
+// Current returns the machine's current state, falling back to Initial if
+// no transition has been taken yet.
+func (m *Machine) Current() StateType {
+	if m.current == "" {
+		return m.Initial
+	}
+	return m.current
+}
+
+// TransitionWithContext applies event, carrying payload, to the machine's
+// current state, returning the resulting state. It returns
+// ErrContextCanceled if ctx is already done, ErrNoSuchTransition if the
+// current state has no transition for event or payload doesn't match the
+// transition's PayloadType, or an *ErrGuardRejected if the transition's
+// Guard vetoes it. If a WAL is attached, the transition is appended and
+// fsync'd before the in-memory state changes; a failed append leaves the
+// machine in its prior state.
+func (m *Machine) TransitionWithContext(ctx context.Context, event EventType, payload any) (StateType, error) {
+	cur := m.Current()
+	if err := ctx.Err(); err != nil {
+		return cur, ErrContextCanceled
+	}
+
+	state, ok := m.States[cur]
+	if !ok {
+		return cur, ErrNoSuchTransition
+	}
+	t, ok := state.On[event]
+	if !ok {
+		return cur, ErrNoSuchTransition
+	}
+
+	if t.PayloadType != nil && (payload == nil || reflect.TypeOf(payload) != t.PayloadType) {
+		return cur, fmt.Errorf("%w: payload type %T does not match expected %s", ErrNoSuchTransition, payload, t.PayloadType)
+	}
+
+	if t.Guard != nil {
+		if err := t.Guard(ctx, payload); err != nil {
+			return cur, &ErrGuardRejected{Reason: err}
+		}
+	}
+
+	raw, _ := payload.([]byte)
+
+	if m.WAL != nil && !m.replaying {
+		if err := m.WAL.AppendTransition(m.ID, string(cur), string(event), string(t.To), raw); err != nil {
+			return cur, fmt.Errorf("statemachine: appending to wal: %w", err)
+		}
+	}
+	if !m.replaying {
+		if h, ok := m.hooks[cur]; ok && h.Exit != nil {
+			_ = h.Exit(m.ID, string(cur))
+		}
+	}
+	m.current = t.To
+	if !m.replaying {
+		if h, ok := m.hooks[t.To]; ok && h.Entry != nil {
+			_ = h.Entry(m.ID, string(t.To))
+		}
+	}
+	if !m.replaying && m.bus != nil {
+		m.bus.Publish(TransitionEvent{
+			MachineID: m.ID,
+			From:      string(cur),
+			To:        string(t.To),
+			Event:     string(event),
+			Timestamp: time.Now(),
+			Payload:   raw,
+		})
+	}
+	return m.current, nil
+}
+
+// Subscribe returns a channel of TransitionEvents matching query, published
+// after each successful transition, along with a CancelFunc that
+// unsubscribes and closes the channel. The channel is buffered per
+// SubscriberBufferSize/SubscriberOverflow (defaulting to 64 and DropOldest).
+func (m *Machine) Subscribe(query TransitionQuery) (<-chan TransitionEvent, CancelFunc) {
+	m.busOnce.Do(m.initBus)
+	size := m.SubscriberBufferSize
+	if size <= 0 {
+		size = defaultSubscriberBuffer
+	}
+	return m.bus.Subscribe(query, size, m.SubscriberOverflow)
+}
+
+// SubscribeUnbuffered returns a channel of TransitionEvents matching query
+// that transitions are sent to synchronously, so a hook reading this
+// channel has received each event before TransitionWithContext returns.
+// Whatever the hook does after the receive is not waited for.
+func (m *Machine) SubscribeUnbuffered(query TransitionQuery) (<-chan TransitionEvent, CancelFunc) {
+	m.busOnce.Do(m.initBus)
+	return m.bus.SubscribeUnbuffered(query)
+}
+
+func (m *Machine) initBus() {
+	m.bus = NewEventBus()
+}
+
+// Restore reopens the WAL rooted at walPath, validates and replays its
+// entries for m.ID, and rebuilds Current() from them. It then attaches the
+// opened WAL to m so subsequent transitions keep appending to it.
+func (m *Machine) Restore(walPath string) error {
+	wal, err := NewFileWAL(walPath, defaultMaxSegmentBytes)
+	if err != nil {
+		return err
+	}
+	entries, err := wal.Replay(m.ID)
+	if err != nil {
+		return err
+	}
+
+	m.replaying = true
+	for _, e := range entries {
+		m.current = StateType(e.To)
+	}
+	m.replaying = false
+
+	m.WAL = wal
+	return nil
+}
@@ -0,0 +1,195 @@
+package statemachine
+
+import (
+	"sync"
+	"time"
+)
+
+// TransitionEvent describes a transition that was just applied.
+type TransitionEvent struct {
+	MachineID string
+	From      string
+	To        string
+	Event     string
+	Timestamp time.Time
+	Payload   []byte
+}
+
+// TransitionQuery filters which TransitionEvents a subscriber receives. A
+// zero-value field matches anything; Predicate, if set, is applied after
+// the other fields match.
+type TransitionQuery struct {
+	From      string
+	To        string
+	Event     string
+	Predicate func(TransitionEvent) bool
+}
+
+func (q TransitionQuery) matches(e TransitionEvent) bool {
+	if q.From != "" && q.From != e.From {
+		return false
+	}
+	if q.To != "" && q.To != e.To {
+		return false
+	}
+	if q.Event != "" && q.Event != e.Event {
+		return false
+	}
+	if q.Predicate != nil && !q.Predicate(e) {
+		return false
+	}
+	return true
+}
+
+// OverflowPolicy controls what happens when a buffered subscriber's
+// channel is full.
+type OverflowPolicy int
+
+const (
+	DropOldest OverflowPolicy = iota
+	DropNewest
+	Block
+)
+
+// CancelFunc unsubscribes and releases the subscription's resources. It is
+// safe to call more than once.
+type CancelFunc func()
+
+// EventBus fans TransitionEvents out to subscribers. It is used by both
+// Machine and the lightswitch StateMachine so external observers can react
+// to transitions without polling Current().
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+// NewEventBus returns an empty EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*subscriber]struct{})}
+}
+
+type subscriber struct {
+	query      TransitionQuery
+	ch         chan TransitionEvent
+	overflow   OverflowPolicy
+	unbuffered bool
+
+	mu       sync.Mutex
+	closed   bool
+	done     chan struct{}
+	inFlight sync.WaitGroup
+}
+
+// Subscribe returns a channel that receives events matching query on a
+// buffer of bufferSize, with overflow handled per policy once it fills.
+func (b *EventBus) Subscribe(query TransitionQuery, bufferSize int, overflow OverflowPolicy) (<-chan TransitionEvent, CancelFunc) {
+	s := &subscriber{query: query, ch: make(chan TransitionEvent, bufferSize), overflow: overflow, done: make(chan struct{})}
+	return s.ch, b.add(s)
+}
+
+// SubscribeUnbuffered returns a channel that receives events matching query
+// synchronously: Publish blocks until the event has been received, so a
+// hook reading this channel has the event before the Transition call that
+// produced it returns. What the hook does after the receive is not waited
+// for. Useful for audit logging or OnExit/OnEntry-style hooks.
+func (b *EventBus) SubscribeUnbuffered(query TransitionQuery) (<-chan TransitionEvent, CancelFunc) {
+	s := &subscriber{query: query, ch: make(chan TransitionEvent), unbuffered: true, done: make(chan struct{})}
+	return s.ch, b.add(s)
+}
+
+func (b *EventBus) add(s *subscriber) CancelFunc {
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, s)
+			b.mu.Unlock()
+			s.close()
+		})
+	}
+}
+
+// Publish delivers e to every matching subscriber. Unbuffered subscribers
+// are sent to synchronously, in the caller's goroutine, before Publish
+// returns.
+func (b *EventBus) Publish(e TransitionEvent) {
+	b.mu.Lock()
+	subs := make([]*subscriber, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		if s.query.matches(e) {
+			s.deliver(e)
+		}
+	}
+}
+
+// close marks the subscriber closed, unblocks any deliver call currently
+// waiting to send (so it can't hold up close forever if nothing is
+// draining ch), waits for those in-flight delivers to actually return,
+// and only then closes ch. Closing ch only after every deliver that saw
+// closed == false has finished is what makes this race-free: deliver
+// calls that arrive after s.closed is set return immediately without
+// touching ch, so nothing can send on it once it's closed.
+func (s *subscriber) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	s.inFlight.Wait()
+	close(s.ch)
+}
+
+func (s *subscriber) deliver(e TransitionEvent) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.inFlight.Add(1)
+	s.mu.Unlock()
+	defer s.inFlight.Done()
+
+	// s.done, closed by close(), lets a blocking send here abort as soon
+	// as the subscriber is unsubscribed instead of holding it up until a
+	// reader shows up (or forever, if one never does).
+	switch {
+	case s.unbuffered, s.overflow == Block:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		}
+	case s.overflow == DropNewest:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		default:
+		}
+	default: // DropOldest
+		for {
+			select {
+			case s.ch <- e:
+				return
+			case <-s.done:
+				return
+			default:
+				select {
+				case <-s.ch:
+				default:
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package statemachine
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUnsubscribeDuringBlockedPublishDoesNotDeadlock reproduces an
+// unbuffered subscriber with no reader: Publish blocks on delivery from
+// a goroutine, and cancel() must still unsubscribe promptly instead of
+// waiting on the same lock the blocked delivery holds.
+func TestUnsubscribeDuringBlockedPublishDoesNotDeadlock(t *testing.T) {
+	b := NewEventBus()
+	_, cancel := b.SubscribeUnbuffered(TransitionQuery{})
+
+	go b.Publish(TransitionEvent{Event: "never read"})
+	// Give Publish a moment to reach the blocking send.
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		cancel()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancel() deadlocked waiting to unsubscribe a blocked, unread subscriber")
+	}
+}
+
+// TestUnsubscribeClosesChannel checks that, once a drained subscriber is
+// unsubscribed, its channel still closes (ok == false) the way callers
+// ranging over it expect.
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	b := NewEventBus()
+	ch, cancel := b.Subscribe(TransitionQuery{}, 4, DropOldest)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected channel to be closed after cancel()")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
+
+func BenchmarkPublishFanOut(b *testing.B) {
+	bus := NewEventBus()
+	const subscribers = 5000
+
+	cancels := make([]CancelFunc, subscribers)
+	for i := range cancels {
+		ch, cancel := bus.Subscribe(TransitionQuery{}, 1, DropOldest)
+		cancels[i] = cancel
+		go func(ch <-chan TransitionEvent) {
+			for range ch {
+			}
+		}(ch)
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Publish(TransitionEvent{Event: "bench"})
+	}
+}
@@ -0,0 +1,63 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+)
+
+// ActionFunc runs as a state's entry or exit hook when loaded from a
+// declarative definition.
+type ActionFunc func(machineID, state string) error
+
+// GuardFunc is evaluated before a transition is taken and can veto it by
+// returning a non-nil error. It sees the same ctx and payload passed to
+// TransitionWithContext; use PayloadAs to recover a typed payload.
+type GuardFunc func(ctx context.Context, payload any) error
+
+// PayloadAs type-asserts payload to T, for use inside a Guard or
+// ActionFunc that expects a specific payload type.
+func PayloadAs[T any](payload any) (T, bool) {
+	v, ok := payload.(T)
+	return v, ok
+}
+
+// Registry resolves the action/guard names used in a declarative
+// definition to Go funcs, so definitions themselves stay pure data.
+type Registry struct {
+	actions map[string]ActionFunc
+	guards  map[string]GuardFunc
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		actions: make(map[string]ActionFunc),
+		guards:  make(map[string]GuardFunc),
+	}
+}
+
+// RegisterAction makes fn available under name for entry/exit references.
+func (r *Registry) RegisterAction(name string, fn ActionFunc) {
+	r.actions[name] = fn
+}
+
+// RegisterGuard makes fn available under name for guard references.
+func (r *Registry) RegisterGuard(name string, fn GuardFunc) {
+	r.guards[name] = fn
+}
+
+func (r *Registry) action(name string) (ActionFunc, error) {
+	fn, ok := r.actions[name]
+	if !ok {
+		return nil, fmt.Errorf("statemachine: no action registered under %q", name)
+	}
+	return fn, nil
+}
+
+func (r *Registry) guard(name string) (GuardFunc, error) {
+	fn, ok := r.guards[name]
+	if !ok {
+		return nil, fmt.Errorf("statemachine: no guard registered under %q", name)
+	}
+	return fn, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/wfen/dingy/experiments/statemachine/service/servicepb"
+)
+
+const defaultRPCTimeout = 5 * time.Second
+
+// Client is a thin convenience wrapper around servicepb.FSMServiceClient.
+type Client struct {
+	conn *grpc.ClientConn
+	pb   servicepb.FSMServiceClient
+}
+
+// DialUnix connects to a Server listening on the Unix domain socket at
+// path.
+func DialUnix(path string) (*Client, error) {
+	conn, err := grpc.Dial("unix:"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("service: dialing %s: %w", path, err)
+	}
+	return &Client{conn: conn, pb: servicepb.NewFSMServiceClient(conn)}, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// CreateMachine loads definition (in format "toml", "yaml", or "json") on
+// the server and returns the resulting machine's id and current state.
+func (c *Client) CreateMachine(ctx context.Context, definition, format string) (id, current string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultRPCTimeout)
+	defer cancel()
+	m, err := c.pb.CreateMachine(ctx, &servicepb.CreateMachineRequest{Definition: definition, Format: format})
+	if err != nil {
+		return "", "", err
+	}
+	return m.GetId(), m.GetCurrent(), nil
+}
+
+// SendEvent applies event to machine id and returns its resulting state.
+func (c *Client) SendEvent(ctx context.Context, id, event string, payload []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultRPCTimeout)
+	defer cancel()
+	m, err := c.pb.SendEvent(ctx, &servicepb.SendEventRequest{Id: id, Event: event, Payload: payload})
+	if err != nil {
+		return "", err
+	}
+	return m.GetCurrent(), nil
+}
+
+// WatchTransitions streams transitions applied to machine id until ctx is
+// canceled, handing each to fn.
+func (c *Client) WatchTransitions(ctx context.Context, id string, fn func(*servicepb.TransitionEvent)) error {
+	stream, err := c.pb.WatchTransitions(ctx, &servicepb.WatchTransitionsRequest{Id: id})
+	if err != nil {
+		return err
+	}
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		fn(e)
+	}
+}
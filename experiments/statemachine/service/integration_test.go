@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/wfen/dingy/experiments/statemachine"
+	"github.com/wfen/dingy/experiments/statemachine/service"
+	"github.com/wfen/dingy/experiments/statemachine/service/servicepb"
+)
+
+// turnstileJSON is the lockUnlock example (see examples/lockUnlock.go)
+// expressed as a declarative definition, so the integration test below
+// drives the same FSM over a Unix socket instead of in-process.
+const turnstileJSON = `{
+	"id": "turnstile",
+	"initial": "locked",
+	"states": {
+		"locked": {"on": {"COIN": "unlocked", "PUSH": "locked"}},
+		"unlocked": {"on": {"COIN": "unlocked", "PUSH": "locked"}}
+	}
+}`
+
+func TestIntegrationTurnstileOverUnixSocket(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "dinghy.sock")
+
+	lis, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listening on %s: %v", sockPath, err)
+	}
+
+	srv, err := service.NewServer(statemachine.NewRegistry(), service.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	servicepb.RegisterFSMServiceServer(grpcServer, srv)
+
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	client, err := service.DialUnix(sockPath)
+	if err != nil {
+		t.Fatalf("DialUnix: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	id, current, err := client.CreateMachine(ctx, turnstileJSON, "json")
+	if err != nil {
+		t.Fatalf("CreateMachine: %v", err)
+	}
+	if id != "turnstile" {
+		t.Fatalf("id = %q, want %q", id, "turnstile")
+	}
+	if current != "locked" {
+		t.Fatalf("current = %q, want %q", current, "locked")
+	}
+
+	steps := []struct {
+		event string
+		want  string
+	}{
+		{"COIN", "unlocked"},
+		{"COIN", "unlocked"},
+		{"PUSH", "locked"},
+		{"COIN", "unlocked"},
+	}
+	for _, step := range steps {
+		got, err := client.SendEvent(ctx, id, step.event, nil)
+		if err != nil {
+			t.Fatalf("SendEvent(%s): %v", step.event, err)
+		}
+		if got != step.want {
+			t.Fatalf("SendEvent(%s) = %q, want %q", step.event, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package service exposes statemachine.Machine instances over gRPC, so
+// dinghy can run as a sidecar workflow engine rather than only an
+// in-process library.
+//
+// FSMServiceServer, FSMServiceClient, and the request/response messages
+// are generated from dinghy.proto; regenerate them with `go generate`.
+package service
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/wfen/dingy/experiments/statemachine/service --go-grpc_out=. --go-grpc_opt=module=github.com/wfen/dingy/experiments/statemachine/service dinghy.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/wfen/dingy/experiments/statemachine"
+	"github.com/wfen/dingy/experiments/statemachine/service/servicepb"
+)
+
+// Server implements servicepb.FSMServiceServer against a Registry of
+// action/guard funcs and a pluggable Store.
+type Server struct {
+	servicepb.UnimplementedFSMServiceServer
+
+	Registry *statemachine.Registry
+	Store    Store
+
+	mu       sync.Mutex
+	machines map[string]*statemachine.Machine
+}
+
+// NewServer returns a Server backed by store, loading any machines already
+// persisted there and resolving action/guard names against registry on
+// every subsequent CreateMachine.
+func NewServer(registry *statemachine.Registry, store Store) (*Server, error) {
+	s := &Server{Registry: registry, Store: store, machines: make(map[string]*statemachine.Machine)}
+	existing, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("service: loading machines: %w", err)
+	}
+	for _, m := range existing {
+		s.machines[m.ID] = m
+	}
+	return s, nil
+}
+
+// CreateMachine implements servicepb.FSMServiceServer.
+func (s *Server) CreateMachine(ctx context.Context, req *servicepb.CreateMachineRequest) (*servicepb.Machine, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	m, err := statemachine.LoadFromBytes([]byte(req.GetDefinition()), statemachine.Format(req.GetFormat()), s.Registry)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "service: invalid definition: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.machines[m.ID]; exists {
+		return nil, status.Errorf(codes.AlreadyExists, "service: machine %q already exists", m.ID)
+	}
+	if err := s.Store.Save(m); err != nil {
+		return nil, status.Errorf(codes.Internal, "service: saving machine: %v", err)
+	}
+	s.machines[m.ID] = m
+	return toProto(m), nil
+}
+
+// DeleteMachine implements servicepb.FSMServiceServer.
+func (s *Server) DeleteMachine(ctx context.Context, req *servicepb.DeleteMachineRequest) (*servicepb.DeleteMachineResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.machines[req.GetId()]; !ok {
+		return nil, status.Errorf(codes.NotFound, "service: no machine %q", req.GetId())
+	}
+	if err := s.Store.Delete(req.GetId()); err != nil {
+		return nil, status.Errorf(codes.Internal, "service: deleting machine: %v", err)
+	}
+	delete(s.machines, req.GetId())
+	return &servicepb.DeleteMachineResponse{}, nil
+}
+
+// GetMachine implements servicepb.FSMServiceServer.
+func (s *Server) GetMachine(ctx context.Context, req *servicepb.GetMachineRequest) (*servicepb.Machine, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.machines[req.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "service: no machine %q", req.GetId())
+	}
+	return toProto(m), nil
+}
+
+// ListMachines implements servicepb.FSMServiceServer.
+func (s *Server) ListMachines(ctx context.Context, _ *servicepb.ListMachinesRequest) (*servicepb.ListMachinesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &servicepb.ListMachinesResponse{Machines: make([]*servicepb.Machine, 0, len(s.machines))}
+	for _, m := range s.machines {
+		resp.Machines = append(resp.Machines, toProto(m))
+	}
+	return resp, nil
+}
+
+// SendEvent implements servicepb.FSMServiceServer.
+func (s *Server) SendEvent(ctx context.Context, req *servicepb.SendEventRequest) (*servicepb.Machine, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.machines[req.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "service: no machine %q", req.GetId())
+	}
+
+	var payload any
+	if p := req.GetPayload(); len(p) > 0 {
+		payload = p
+	}
+	if _, err := m.TransitionWithContext(ctx, statemachine.EventType(req.GetEvent()), payload); err != nil {
+		return nil, transitionStatus(err)
+	}
+	if err := s.Store.Save(m); err != nil {
+		return nil, status.Errorf(codes.Internal, "service: saving machine: %v", err)
+	}
+	return toProto(m), nil
+}
+
+// transitionStatus maps the structured errors TransitionWithContext returns
+// to the closest gRPC status code.
+func transitionStatus(err error) error {
+	switch {
+	case errors.Is(err, statemachine.ErrContextCanceled):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, statemachine.ErrNoSuchTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		var rejected *statemachine.ErrGuardRejected
+		if errors.As(err, &rejected) {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// WatchTransitions implements servicepb.FSMServiceServer, streaming the
+// transitions applied to the named machine until the client cancels or
+// disconnects. Events pass through the machine's buffered Subscribe
+// channel, so a client that falls behind loses events per the machine's
+// SubscriberBufferSize and SubscriberOverflow (by default, the oldest
+// beyond 64 are dropped).
+func (s *Server) WatchTransitions(req *servicepb.WatchTransitionsRequest, stream servicepb.FSMService_WatchTransitionsServer) error {
+	s.mu.Lock()
+	m, ok := s.machines[req.GetId()]
+	s.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "service: no machine %q", req.GetId())
+	}
+
+	events, cancel := m.Subscribe(statemachine.TransitionQuery{})
+	defer cancel()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(toProtoEvent(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toProto(m *statemachine.Machine) *servicepb.Machine {
+	return &servicepb.Machine{Id: m.ID, Current: string(m.Current())}
+}
+
+func toProtoEvent(e statemachine.TransitionEvent) *servicepb.TransitionEvent {
+	return &servicepb.TransitionEvent{
+		MachineId:         e.MachineID,
+		From:              e.From,
+		To:                e.To,
+		Event:             e.Event,
+		TimestampUnixNano: e.Timestamp.UnixNano(),
+		Payload:           e.Payload,
+	}
+}
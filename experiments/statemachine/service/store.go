@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/wfen/dingy/experiments/statemachine"
+)
+
+// Store persists Machines so a Server survives restarts.
+type Store interface {
+	Save(m *statemachine.Machine) error
+	Load(id string) (*statemachine.Machine, bool, error)
+	Delete(id string) error
+	List() ([]*statemachine.Machine, error)
+}
+
+// MemoryStore is an in-process Store, useful for tests and single-binary
+// deployments.
+type MemoryStore struct {
+	mu       sync.Mutex
+	machines map[string]*statemachine.Machine
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{machines: make(map[string]*statemachine.Machine)}
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(m *statemachine.Machine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.machines[m.ID] = m
+	return nil
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(id string) (*statemachine.Machine, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.machines[id]
+	return m, ok, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.machines[id]; !ok {
+		return fmt.Errorf("service: no machine %q", id)
+	}
+	delete(s.machines, id)
+	return nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List() ([]*statemachine.Machine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*statemachine.Machine, 0, len(s.machines))
+	for _, m := range s.machines {
+		out = append(out, m)
+	}
+	return out, nil
+}
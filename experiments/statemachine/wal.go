@@ -0,0 +1,345 @@
+package statemachine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Entry is a single recorded transition in a WAL.
+type Entry struct {
+	MachineID string
+	From      string
+	Event     string
+	To        string
+	Payload   []byte
+}
+
+// WAL persists transitions so a Machine can be rebuilt after a crash.
+type WAL interface {
+	// AppendTransition durably records a proposed transition before it is
+	// applied in memory.
+	AppendTransition(machineID, from, event, to string, payload []byte) error
+	// Replay returns every entry recorded for machineID, in order.
+	Replay(machineID string) ([]Entry, error)
+}
+
+const defaultMaxSegmentBytes = 16 << 20 // 16MiB
+
+const segmentPrefix = "segment-"
+const snapshotPrefix = "snapshot-"
+
+// FileWAL is the default file-backed WAL. Records are length-prefixed
+// (varint length + CRC32 checksum + JSON payload) and appended to a segment
+// file in dir, fsync'd on every commit. Segments rotate once they exceed
+// maxSegmentBytes.
+type FileWAL struct {
+	mu              sync.Mutex
+	dir             string
+	maxSegmentBytes int64
+
+	file    *os.File
+	written int64
+	segment int
+}
+
+// NewFileWAL opens (creating if necessary) a file-backed WAL rooted at dir.
+func NewFileWAL(dir string, maxSegmentBytes int64) (*FileWAL, error) {
+	if maxSegmentBytes <= 0 {
+		maxSegmentBytes = defaultMaxSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("statemachine: creating wal dir: %w", err)
+	}
+	w := &FileWAL{dir: dir, maxSegmentBytes: maxSegmentBytes}
+	segments, err := w.segmentPaths()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		if err := w.openSegment(0); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+	last := segments[len(segments)-1]
+	n, err := segmentNumber(last)
+	if err != nil {
+		return nil, err
+	}
+	// Appends go to a fresh segment rather than the last one, so a torn
+	// record that a crash left at the end of that segment stays its tail.
+	if err := w.openSegment(n + 1); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *FileWAL) segmentPaths() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(w.dir, segmentPrefix+"*.wal"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func segmentNumber(path string) (int, error) {
+	base := filepath.Base(path)
+	var n int
+	_, err := fmt.Sscanf(base, segmentPrefix+"%06d.wal", &n)
+	return n, err
+}
+
+func (w *FileWAL) openSegment(n int) error {
+	path := filepath.Join(w.dir, fmt.Sprintf(segmentPrefix+"%06d.wal", n))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("statemachine: opening wal segment: %w", err)
+	}
+	w.file = f
+	w.written = 0
+	w.segment = n
+	return nil
+}
+
+func (w *FileWAL) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	return w.openSegment(w.segment + 1)
+}
+
+// AppendTransition implements WAL.
+func (w *FileWAL) AppendTransition(machineID, from, event, to string, payload []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e := Entry{MachineID: machineID, From: from, Event: event, To: to, Payload: payload}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("statemachine: encoding wal entry: %w", err)
+	}
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(body)))
+	checksum := crc32.ChecksumIEEE(body)
+
+	record := make([]byte, 0, n+4+len(body))
+	record = append(record, lenBuf[:n]...)
+	var crcBuf [4]byte
+	binary.BigEndian.PutUint32(crcBuf[:], checksum)
+	record = append(record, crcBuf[:]...)
+	record = append(record, body...)
+
+	if _, err := w.file.Write(record); err != nil {
+		return fmt.Errorf("statemachine: appending wal record: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("statemachine: fsync wal segment: %w", err)
+	}
+	w.written += int64(len(record))
+
+	if w.written >= w.maxSegmentBytes {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("statemachine: rotating wal segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Replay implements WAL. Segments are read in order; a record that fails its
+// checksum (a torn write left by a crash mid-append) ends replay of that
+// segment rather than returning an error, since it can only be the tail.
+func (w *FileWAL) Replay(machineID string) ([]Entry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	segments, err := w.segmentPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if snap, ok, err := w.loadSnapshot(machineID); err != nil {
+		return nil, err
+	} else if ok {
+		entries = append(entries, snap)
+	}
+
+	for _, path := range segments {
+		segEntries, err := readSegment(path)
+		if err != nil {
+			return nil, fmt.Errorf("statemachine: reading segment %s: %w", path, err)
+		}
+		for _, e := range segEntries {
+			if e.MachineID == machineID {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+func readSegment(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var entries []Entry
+	for {
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			// A partial varint at EOF is a torn record; stop here.
+			break
+		}
+		var crcBuf [4]byte
+		if _, err := io.ReadFull(r, crcBuf[:]); err != nil {
+			break
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(crcBuf[:]) {
+			break
+		}
+		var e Entry
+		if err := json.Unmarshal(body, &e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// snapshot is written by Compact and gives Replay a base state to start
+// from instead of the full transition history. Segment is the number of
+// the active segment at the time of compaction: every earlier segment's
+// entries for MachineID are reflected in State, so once every machine
+// with entries in a given segment has a snapshot whose Segment is past
+// it, that segment carries no information Replay still needs.
+type snapshot struct {
+	MachineID string
+	State     string
+	Segment   int
+}
+
+func (w *FileWAL) loadSnapshotRaw(machineID string) (snapshot, bool, error) {
+	path := filepath.Join(w.dir, snapshotPrefix+machineID+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshot{}, false, nil
+		}
+		return snapshot{}, false, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snapshot{}, false, err
+	}
+	return snap, true, nil
+}
+
+func (w *FileWAL) loadSnapshot(machineID string) (Entry, bool, error) {
+	snap, ok, err := w.loadSnapshotRaw(machineID)
+	if err != nil || !ok {
+		return Entry{}, ok, err
+	}
+	return Entry{MachineID: snap.MachineID, To: snap.State}, true, nil
+}
+
+// Compact snapshots state as the current state for machineID and discards
+// whichever prior segments are now fully covered, so future Replay calls
+// only need to re-apply transitions recorded after the snapshot. A
+// directory's segments are shared by every machine appending to this WAL,
+// so a segment is only discarded once every machine with an entry in it
+// has its own snapshot recorded past that segment; otherwise, discarding
+// it here would silently erase another machine's un-compacted history.
+func (w *FileWAL) Compact(machineID, state string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snap := snapshot{MachineID: machineID, State: state, Segment: w.segment}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, snapshotPrefix+machineID+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("statemachine: writing snapshot: %w", err)
+	}
+
+	segments, err := w.segmentPaths()
+	if err != nil {
+		return err
+	}
+	// The active segment keeps receiving writes, so it's never a candidate
+	// for removal.
+	for _, path := range segments {
+		if path == w.file.Name() {
+			continue
+		}
+		n, err := segmentNumber(path)
+		if err != nil {
+			return err
+		}
+		covered, err := w.segmentFullyCompacted(path, n)
+		if err != nil {
+			return err
+		}
+		if !covered {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("statemachine: removing compacted segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// segmentFullyCompacted reports whether every machine with an entry in
+// the segment at path (segment number n) has a snapshot recorded for a
+// later segment, meaning the segment holds nothing Replay still needs for
+// any machine.
+func (w *FileWAL) segmentFullyCompacted(path string, n int) (bool, error) {
+	entries, err := readSegment(path)
+	if err != nil {
+		return false, fmt.Errorf("statemachine: reading segment %s: %w", path, err)
+	}
+	checked := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if checked[e.MachineID] {
+			continue
+		}
+		checked[e.MachineID] = true
+		snap, ok, err := w.loadSnapshotRaw(e.MachineID)
+		if err != nil {
+			return false, err
+		}
+		if !ok || snap.Segment <= n {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Close releases the WAL's open segment file.
+func (w *FileWAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
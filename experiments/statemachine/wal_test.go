@@ -0,0 +1,139 @@
+package statemachine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileWALReplayIdempotence(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewFileWAL(dir, 0)
+	if err != nil {
+		t.Fatalf("NewFileWAL: %v", err)
+	}
+	for _, e := range []struct{ from, event, to string }{
+		{"locked", "COIN", "unlocked"},
+		{"unlocked", "PUSH", "locked"},
+		{"locked", "COIN", "unlocked"},
+	} {
+		if err := w.AppendTransition("turnstile", e.from, e.event, e.to, nil); err != nil {
+			t.Fatalf("AppendTransition: %v", err)
+		}
+	}
+
+	first, err := w.Replay("turnstile")
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	second, err := w.Replay("turnstile")
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(first) != len(second) {
+		t.Fatalf("replay returned %d entries then %d", len(first), len(second))
+	}
+	for i := range first {
+		if !reflect.DeepEqual(first[i], second[i]) {
+			t.Fatalf("entry %d differs between replays: %+v vs %+v", i, first[i], second[i])
+		}
+	}
+	if len(first) != 3 {
+		t.Fatalf("got %d entries, want 3", len(first))
+	}
+}
+
+func TestFileWALReplayTornWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewFileWAL(dir, 0)
+	if err != nil {
+		t.Fatalf("NewFileWAL: %v", err)
+	}
+	if err := w.AppendTransition("m1", "a", "go", "b", nil); err != nil {
+		t.Fatalf("AppendTransition: %v", err)
+	}
+	if err := w.AppendTransition("m1", "b", "go", "c", nil); err != nil {
+		t.Fatalf("AppendTransition: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Simulate a crash mid-append by truncating the segment so its last
+	// record is torn.
+	matches, err := filepath.Glob(filepath.Join(dir, segmentPrefix+"*.wal"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected exactly one segment, got %v (err %v)", matches, err)
+	}
+	info, err := os.Stat(matches[0])
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if err := os.Truncate(matches[0], info.Size()-2); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+
+	w2, err := NewFileWAL(dir, 0)
+	if err != nil {
+		t.Fatalf("reopening wal: %v", err)
+	}
+	entries, err := w2.Replay("m1")
+	if err != nil {
+		t.Fatalf("Replay after torn write: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries after torn write, want 1 (the intact record)", len(entries))
+	}
+	if entries[0].To != "b" {
+		t.Fatalf("entries[0].To = %q, want %q", entries[0].To, "b")
+	}
+}
+
+func TestFileWALCompactDoesNotDropOtherMachines(t *testing.T) {
+	dir := t.TempDir()
+
+	// A tiny max segment size forces a rotation between the two machines'
+	// interleaved entries below.
+	w, err := NewFileWAL(dir, 1)
+	if err != nil {
+		t.Fatalf("NewFileWAL: %v", err)
+	}
+	if err := w.AppendTransition("machineA", "s0", "go", "s1", nil); err != nil {
+		t.Fatalf("AppendTransition(A): %v", err)
+	}
+	if err := w.AppendTransition("machineB", "s0", "go", "s1", nil); err != nil {
+		t.Fatalf("AppendTransition(B): %v", err)
+	}
+	if err := w.AppendTransition("machineA", "s1", "go", "s2", nil); err != nil {
+		t.Fatalf("AppendTransition(A): %v", err)
+	}
+
+	if err := w.Compact("machineA", "s2"); err != nil {
+		t.Fatalf("Compact(machineA): %v", err)
+	}
+
+	entries, err := w.Replay("machineB")
+	if err != nil {
+		t.Fatalf("Replay(machineB): %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Replay(machineB) returned %d entries after Compact(machineA), want 1 (machineB was never compacted)", len(entries))
+	}
+
+	if err := w.Compact("machineB", "s1"); err != nil {
+		t.Fatalf("Compact(machineB): %v", err)
+	}
+	segments, err := w.segmentPaths()
+	if err != nil {
+		t.Fatalf("segmentPaths: %v", err)
+	}
+	for _, path := range segments {
+		if path == w.file.Name() {
+			continue
+		}
+		t.Fatalf("segment %s should have been removed once both machines were compacted", path)
+	}
+}